@@ -1,12 +1,13 @@
 // package captcha is a package for verifying captcha responses from Cloudflare Turnstile, Google
-// reCAPTCHA v2 (checkbox and invisible), reCAPTCHA v3 or any other custom endpoint.
+// reCAPTCHA v2 (checkbox and invisible), reCAPTCHA v3, hCaptcha or any other custom endpoint.
 //
 // ## Why build another library?
 //
 // This has been done before, but I had a list of requirements that weren't quite met by one I
 // found:
 //
-// - Supports Cloudflare Turnstile, Google reCAPTCHA v2, reCAPTCHA v3 (and any custom endpoint),
+// - Supports Cloudflare Turnstile, Google reCAPTCHA v2, reCAPTCHA v3, hCaptcha (and any custom
+//   endpoint),
 // - Actually checks `Hostname`/`ApkPackageName` and `Action`,
 // - Doesn't use a web framework except the standard library,
 // - Allows setting a custom `http.Client` for requests,
@@ -29,9 +30,11 @@ package captcha
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"net/url"
 	"time"
 )
 
@@ -46,6 +49,10 @@ const GoogleRecaptcha Endpoint = "https://www.google.com/recaptcha/api/siteverif
 // https://developers.cloudflare.com/turnstile/get-started/server-side-validation/
 const CloudflareTurnstile Endpoint = "https://challenges.cloudflare.com/turnstile/v0/siteverify"
 
+// HCaptcha endpoint, see https://docs.hcaptcha.com/#verify-the-user-response-server-side. hCaptcha
+// requires `EncodingForm`, which `NewCaptchaVerifier` defaults to for this endpoint.
+const HCaptcha Endpoint = "https://hcaptcha.com/siteverify"
+
 // ErrorNon200StatusCode is an error returned when the endpoint returns a HTTP status code which
 // isn't 200.
 type ErrorNon200StatusCode struct {
@@ -57,6 +64,20 @@ func (err ErrorNon200StatusCode) Error() string {
 	return fmt.Sprint("captcha verify endpoint returned non-200 status:", err.StatusCode)
 }
 
+// RequestEncoding controls how a `VerifyRequest` is encoded on the wire.
+type RequestEncoding string
+
+const (
+	// EncodingJSON sends the request body as `application/json`. This is what Cloudflare Turnstile
+	// expects.
+	EncodingJSON RequestEncoding = "json"
+
+	// EncodingForm sends the request body as `application/x-www-form-urlencoded`. This is what
+	// Google reCAPTCHA's siteverify endpoint documents, and is also accepted by Cloudflare
+	// Turnstile and hCaptcha.
+	EncodingForm RequestEncoding = "form"
+)
+
 // CaptchaVerifier is a client for verifying captchas! It provides direct access to the
 // `VerifyResponse` returned by the endpoint, for a more abstract type which returns a boolean, you
 // can construct a `SimpleCaptchaVerifier`.
@@ -64,15 +85,42 @@ func (err ErrorNon200StatusCode) Error() string {
 // It supports any custom endpoint, including Cloudflare Turnstile, Google reCAPTCHA v2 and
 // reCAPTCHA v3.
 type CaptchaVerifier struct {
-	HttpClient      *http.Client
+	HttpClient *http.Client
+
+	// Encoding controls how requests to `captchaEndpoint` are encoded. `NewCaptchaVerifier` picks
+	// a sensible default for `GoogleRecaptcha`, `CloudflareTurnstile` and `HCaptcha`, but this can
+	// be overridden for custom endpoints.
+	Encoding RequestEncoding
+
+	// Now returns the current time, used by `SimpleCaptchaVerifier.MaxChallengeAge` to judge
+	// challenge freshness. Defaults to `time.Now`; override for testing.
+	Now func() time.Time
+
 	captchaEndpoint Endpoint
 	captchaSecret   string
 }
 
+// now returns `client.Now()`, or the real current time if `Now` isn't set.
+func (client *CaptchaVerifier) now() time.Time {
+	if client.Now != nil {
+		return client.Now()
+	}
+	return time.Now()
+}
+
 // NewCaptchaVerifier creates a new `CaptchaVerifier` with `http.DefaultClient`.
+//
+// `Encoding` defaults to `EncodingForm` for `GoogleRecaptcha` and `HCaptcha`, since neither accepts
+// JSON, and to `EncodingJSON` otherwise.
 func NewCaptchaVerifier(captchaEndpoint Endpoint, captchaSecret string) *CaptchaVerifier {
+	encoding := EncodingJSON
+	if captchaEndpoint == GoogleRecaptcha || captchaEndpoint == HCaptcha {
+		encoding = EncodingForm
+	}
 	return &CaptchaVerifier{
 		HttpClient:      http.DefaultClient,
+		Now:             time.Now,
+		Encoding:        encoding,
 		captchaEndpoint: captchaEndpoint,
 		captchaSecret:   captchaSecret,
 	}
@@ -86,29 +134,47 @@ type VerifyRequest struct {
 	// Response provided by the client
 	Response string `json:"response"`
 
-	// RemoteIP is, optionally, the clients IP address
-	RemoteIP string `json:"remoteip"`
+	// RemoteIP is, optionally, the clients IP address. It's omitted from the request entirely when
+	// empty, since some servers reject an empty `remoteip` field.
+	RemoteIP string `json:"remoteip,omitempty"`
 }
 
 // Verify sends this request to an `endpoint` and returns the `VerifyResponse`.
 //
 // Most of the time you probably want to use the `Verify` method on `CaptchaVerifier` or
 // `SimpleCaptchaVerifier` instead.
+//
+// This calls `VerifyContext` with `context.Background()`.
 func (req *VerifyRequest) Verify(
 	client *http.Client,
 	endpoint Endpoint,
+	encoding RequestEncoding,
+) (resp *VerifyResponse, err error) {
+	return req.VerifyContext(context.Background(), client, endpoint, encoding)
+}
+
+// VerifyContext is like `Verify`, but takes a `context.Context` which is attached to the outgoing
+// HTTP request, allowing it to be cancelled or given a deadline by the caller.
+func (req *VerifyRequest) VerifyContext(
+	ctx context.Context,
+	client *http.Client,
+	endpoint Endpoint,
+	encoding RequestEncoding,
 ) (resp *VerifyResponse, err error) {
 	// Format request
-	jsonReq, err := json.Marshal(req)
+	body, contentType, err := req.encode(encoding)
 	if err != nil {
 		return nil, fmt.Errorf("failed to format verify request: %w", err)
 	}
 
 	// Make the POST request
-	httpResp, err := client.Post(
-		string(endpoint),
-		"application/json", bytes.NewReader(jsonReq),
-	)
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, string(endpoint), body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build captcha verify request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", contentType)
+
+	httpResp, err := client.Do(httpReq)
 	if err != nil {
 		return nil, fmt.Errorf("failed to perform POST to captcha verify endpoint: %w", err)
 	}
@@ -126,7 +192,36 @@ func (req *VerifyRequest) Verify(
 	return
 }
 
+// encode formats the request body and returns the `Content-Type` to send it with. `EncodingForm`
+// is used for anything other than `EncodingJSON`, so a zero-value `RequestEncoding` behaves like
+// `EncodingForm`.
+func (req *VerifyRequest) encode(encoding RequestEncoding) (body *bytes.Reader, contentType string, err error) {
+	if encoding == EncodingJSON {
+		jsonReq, err := json.Marshal(req)
+		if err != nil {
+			return nil, "", err
+		}
+		return bytes.NewReader(jsonReq), "application/json", nil
+	}
+
+	form := url.Values{}
+	form.Set("secret", req.Secret)
+	form.Set("response", req.Response)
+	if req.RemoteIP != "" {
+		form.Set("remoteip", req.RemoteIP)
+	}
+	return bytes.NewReader([]byte(form.Encode())), "application/x-www-form-urlencoded", nil
+}
+
 // VerifyResponse is the data returned by the API endpoint.
+//
+// Not every provider populates every field; see the per-field docs, or this summary:
+//
+//	                  Score  ChallengeTime  Action  Hostname  ApkPackageName  CData  Credit
+//	reCAPTCHA v2        no        yes         no       yes         yes         no      no
+//	reCAPTCHA v3        yes       yes         yes      yes         yes         no      no
+//	Cloudflare Turnstile no       yes         yes      yes         no          yes     no
+//	hCaptcha            no        yes         no       yes         no          no      yes
 type VerifyResponse struct {
 	// Success will be true iff the validation was successful. This should be returned by all APIs.
 	Success bool `json:"success"`
@@ -164,13 +259,26 @@ type VerifyResponse struct {
 	// https://developers.cloudflare.com/turnstile/get-started/server-side-validation/#error-codes
 	// and https://developers.google.com/recaptcha/docs/verify#error_code_reference
 	//
-	// In addition, most error codes are provided as documented constant in this package.
-	ErrorCodes []string `json:"error-codes"`
+	// In addition, most error codes are provided as documented constant in this package. Use
+	// `FirstError`, or check individual codes with `ErrorCode.IsClientError`/`IsServerError`,
+	// instead of comparing strings directly.
+	ErrorCodes ErrorCodes `json:"error-codes"`
 
 	// CData is customer data passed on the client side.
 	//
 	// Provided by Cloudflare Turnstile but not reCAPTCHA.
 	CData string `json:"cdata"`
+
+	// Credit indicates whether the response will be credited to the site owner's hCaptcha account.
+	//
+	// Only provided by hCaptcha; the zero value is harmless for reCAPTCHA and Turnstile.
+	Credit bool `json:"credit"`
+
+	// nowFunc is the clock to use for challenge-age checks (`WithMaxChallengeAge`,
+	// `SimpleCaptchaVerifier.MaxChallengeAge`). `CaptchaVerifier.VerifyContext` populates this from
+	// `CaptchaVerifier.Now`, so every freshness check on a given response agrees on the current
+	// time, however it was obtained.
+	nowFunc func() time.Time
 }
 
 // ParsedChallengeTime returns `resp.ChallengeTime` parsed in the RFC3339 layout.
@@ -178,20 +286,45 @@ func (resp *VerifyResponse) ParsedChallengeTime() (time.Time, error) {
 	return time.Parse(time.RFC3339, resp.ChallengeTime)
 }
 
+// now returns `resp.nowFunc()`, or the real current time if `resp` wasn't produced by
+// `CaptchaVerifier.VerifyContext` (e.g. one built directly in a test).
+func (resp *VerifyResponse) now() time.Time {
+	if resp.nowFunc != nil {
+		return resp.nowFunc()
+	}
+	return time.Now()
+}
+
 // Attempt to verify a captcha response, optionally verifying the client IP. This returns the
 // `VerifyResponse`, which you must yourself validate. Use `SimpleCaptchaVerifier` to automatically
 // verify this.
 //
 // Leave `remoteIP` empty to not verify the IP address.
+//
+// This calls `VerifyContext` with `context.Background()`.
 func (client *CaptchaVerifier) Verify(
 	clientResponse,
 	remoteIP string,
 ) (resp *VerifyResponse, err error) {
-	return (&VerifyRequest{
+	return client.VerifyContext(context.Background(), clientResponse, remoteIP)
+}
+
+// VerifyContext is like `Verify`, but takes a `context.Context` which is attached to the outgoing
+// HTTP request, allowing it to be cancelled or given a deadline by the caller.
+func (client *CaptchaVerifier) VerifyContext(
+	ctx context.Context,
+	clientResponse,
+	remoteIP string,
+) (resp *VerifyResponse, err error) {
+	resp, err = (&VerifyRequest{
 		Secret:   client.captchaSecret,
 		Response: clientResponse,
 		RemoteIP: remoteIP,
-	}).Verify(client.HttpClient, client.captchaEndpoint)
+	}).VerifyContext(ctx, client.HttpClient, client.captchaEndpoint, client.Encoding)
+	if resp != nil {
+		resp.nowFunc = client.now
+	}
+	return resp, err
 }
 
 // SimpleCaptchaVerifier wraps a `CaptchaVerifier` with some expected response values. The `Verify`
@@ -219,6 +352,23 @@ type SimpleCaptchaVerifier struct {
 	// ExpectedApkPackageName is the expected value of the `ApkPackageName` field. If using an
 	// web app, this should be an empty string and `ExpectedHostname` should be set.
 	ExpectedApkPackageName string
+
+	// MaxChallengeAge, if positive, rejects challenges older than this, based on `ChallengeTime`
+	// and `Verifier.Now`. This guards against a captcha response being replayed long after it was
+	// solved. Zero or a negative value disables the check.
+	//
+	// Some providers (notably reCAPTCHA v2) sometimes omit `ChallengeTime` entirely; when
+	// `MaxChallengeAge` is positive, a missing or unparseable `ChallengeTime` fails verification
+	// rather than being ignored.
+	MaxChallengeAge time.Duration
+}
+
+// checkChallengeAge validates `resp.ChallengeTime` against `verifier.MaxChallengeAge`, returning
+// an `ErrChallengeExpired` if the challenge is too old, or if its age can't be determined and one
+// is required. It shares its logic with `WithMaxChallengeAge`, so the two freshness checks can't
+// drift out of sync.
+func (verifier SimpleCaptchaVerifier) checkChallengeAge(resp *VerifyResponse) error {
+	return WithMaxChallengeAge(verifier.MaxChallengeAge)(resp)
 }
 
 // Verify a captcha response, optionally verifying the client IP. The response will be validated
@@ -226,8 +376,23 @@ type SimpleCaptchaVerifier struct {
 // the list of error codes must be empty.
 //
 // Leave `remoteIP` empty to not verify the IP address.
+//
+// This calls `VerifyContext` with `context.Background()`.
 func (verifier SimpleCaptchaVerifier) Verify(clientResponse, remoteIP string) (bool, error) {
-	return verifier.VerifyAction(clientResponse, remoteIP, verifier.ExpectedAction)
+	return verifier.VerifyContext(context.Background(), clientResponse, remoteIP)
+}
+
+// VerifyContext is like `Verify`, but takes a `context.Context` which is attached to the outgoing
+// HTTP request, allowing it to be cancelled or given a deadline by the caller.
+func (verifier SimpleCaptchaVerifier) VerifyContext(
+	ctx context.Context,
+	clientResponse,
+	remoteIP string,
+) (bool, error) {
+	_, ok, err := verifier.VerifyActionWithResponseContext(
+		ctx, clientResponse, remoteIP, verifier.ExpectedAction,
+	)
+	return ok, err
 }
 
 // VerifyWithResponse is like Verify, but also returns the VerifyResponse.
@@ -244,33 +409,48 @@ func (verifier SimpleCaptchaVerifier) VerifyWithResponse(
 // true, and the list of error codes must be empty.
 //
 // Leave `remoteIP` empty to not verify the IP address.
+//
+// This calls `VerifyActionWithResponseContext` with `context.Background()`.
 func (verifier SimpleCaptchaVerifier) VerifyAction(
 	clientResponse,
 	remoteIP,
 	expectedAction string,
 ) (bool, error) {
-	resp, err := verifier.Verifier.Verify(clientResponse, remoteIP)
-	if err != nil {
-		return false, err
-	}
-	return resp.Success &&
-		len(resp.ErrorCodes) == 0 &&
-		resp.Score >= verifier.MinScore &&
-		resp.Action == expectedAction &&
-		resp.Hostname == verifier.ExpectedHostname &&
-		resp.ApkPackageName == verifier.ExpectedApkPackageName, nil
+	_, ok, err := verifier.VerifyActionWithResponseContext(
+		context.Background(), clientResponse, remoteIP, expectedAction,
+	)
+	return ok, err
 }
 
 // VerifyActionWithResponse is like VerifyAction, but also returns the VerifyResponse.
+//
+// This calls `VerifyActionWithResponseContext` with `context.Background()`.
 func (verifier SimpleCaptchaVerifier) VerifyActionWithResponse(
 	clientResponse,
 	remoteIP,
 	expectedAction string,
 ) (*VerifyResponse, bool, error) {
-	resp, err := verifier.Verifier.Verify(clientResponse, remoteIP)
+	return verifier.VerifyActionWithResponseContext(
+		context.Background(), clientResponse, remoteIP, expectedAction,
+	)
+}
+
+// VerifyActionWithResponseContext is like VerifyActionWithResponse, but takes a `context.Context`
+// which is attached to the outgoing HTTP request, allowing it to be cancelled or given a deadline
+// by the caller.
+func (verifier SimpleCaptchaVerifier) VerifyActionWithResponseContext(
+	ctx context.Context,
+	clientResponse,
+	remoteIP,
+	expectedAction string,
+) (*VerifyResponse, bool, error) {
+	resp, err := verifier.Verifier.VerifyContext(ctx, clientResponse, remoteIP)
 	if err != nil {
 		return nil, false, err
 	}
+	if err := verifier.checkChallengeAge(resp); err != nil {
+		return resp, false, err
+	}
 	return resp, resp.Success &&
 		len(resp.ErrorCodes) == 0 &&
 		resp.Score >= verifier.MinScore &&
@@ -278,26 +458,3 @@ func (verifier SimpleCaptchaVerifier) VerifyActionWithResponse(
 		resp.Hostname == verifier.ExpectedHostname &&
 		resp.ApkPackageName == verifier.ExpectedApkPackageName, nil
 }
-
-// ErrorCodeMissingInputSecret is caused when the secret is not passed.
-const ErrorCodeMissingInputSecret string = "missing-input-secret"
-
-// ErrorCodeInvalidInputSecret is caused when the secret is invalid.
-const ErrorCodeInvalidInputSecret string = "invalid-input-secret"
-
-// ErrorCodeMissingInputResponse is caused when the response is not passed.
-const ErrorCodeMissingInputResponse string = "missing-input-response"
-
-// ErrorCodeInvalidInputResponse is caused when the response is not valid.
-const ErrorCodeInvalidInputResponse string = "invalid-input-response"
-
-// ErrorCodeBadRequest is caused by a malformed request.
-const ErrorCodeBadRequest string = "bad-request"
-
-// ErrorCodeTimeoutOrDuplicate is caused when the response is either too old or has been used
-// previously.
-const ErrorCodeTimeoutOrDuplicate string = "timeout-or-duplicate"
-
-// ErrorCodeInternalError is caused when an unknown internal error has occurred. The request can be
-// retried.
-const ErrorCodeInternalError string = "internal-error"
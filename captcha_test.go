@@ -0,0 +1,127 @@
+package captcha
+
+import (
+	"io"
+	"testing"
+	"time"
+)
+
+func TestSimpleCaptchaVerifierCheckChallengeAge(t *testing.T) {
+	now := time.Date(2026, 7, 25, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name            string
+		maxChallengeAge time.Duration
+		challengeTime   string
+		wantErr         bool
+		wantAge         time.Duration
+	}{
+		{
+			name:            "disabled when zero",
+			maxChallengeAge: 0,
+			challengeTime:   "",
+			wantErr:         false,
+		},
+		{
+			name:            "disabled when negative",
+			maxChallengeAge: -time.Minute,
+			challengeTime:   "",
+			wantErr:         false,
+		},
+		{
+			name:            "missing challenge time fails closed",
+			maxChallengeAge: time.Minute,
+			challengeTime:   "",
+			wantErr:         true,
+		},
+		{
+			name:            "within max age",
+			maxChallengeAge: time.Minute,
+			challengeTime:   now.Add(-30 * time.Second).Format(time.RFC3339),
+			wantErr:         false,
+		},
+		{
+			name:            "older than max age",
+			maxChallengeAge: time.Minute,
+			challengeTime:   now.Add(-2 * time.Minute).Format(time.RFC3339),
+			wantErr:         true,
+			wantAge:         2 * time.Minute,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			verifier := SimpleCaptchaVerifier{MaxChallengeAge: test.maxChallengeAge}
+			resp := &VerifyResponse{
+				ChallengeTime: test.challengeTime,
+				nowFunc:       func() time.Time { return now },
+			}
+
+			err := verifier.checkChallengeAge(resp)
+			if test.wantErr != (err != nil) {
+				t.Fatalf("checkChallengeAge() error = %v, wantErr %v", err, test.wantErr)
+			}
+			if expired, ok := err.(ErrChallengeExpired); ok && expired.Age != test.wantAge {
+				t.Errorf("ErrChallengeExpired.Age = %v, want %v", expired.Age, test.wantAge)
+			}
+		})
+	}
+}
+
+func TestVerifyRequestEncode(t *testing.T) {
+	tests := []struct {
+		name            string
+		req             VerifyRequest
+		encoding        RequestEncoding
+		wantBody        string
+		wantContentType string
+	}{
+		{
+			name:            "JSON with remote IP",
+			req:             VerifyRequest{Secret: "s", Response: "r", RemoteIP: "1.2.3.4"},
+			encoding:        EncodingJSON,
+			wantBody:        `{"secret":"s","response":"r","remoteip":"1.2.3.4"}`,
+			wantContentType: "application/json",
+		},
+		{
+			name:            "JSON without remote IP omits the field",
+			req:             VerifyRequest{Secret: "s", Response: "r"},
+			encoding:        EncodingJSON,
+			wantBody:        `{"secret":"s","response":"r"}`,
+			wantContentType: "application/json",
+		},
+		{
+			name:            "form with remote IP",
+			req:             VerifyRequest{Secret: "s", Response: "r", RemoteIP: "1.2.3.4"},
+			encoding:        EncodingForm,
+			wantBody:        "remoteip=1.2.3.4&response=r&secret=s",
+			wantContentType: "application/x-www-form-urlencoded",
+		},
+		{
+			name:            "form without remote IP omits the field",
+			req:             VerifyRequest{Secret: "s", Response: "r"},
+			encoding:        EncodingForm,
+			wantBody:        "response=r&secret=s",
+			wantContentType: "application/x-www-form-urlencoded",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			body, contentType, err := test.req.encode(test.encoding)
+			if err != nil {
+				t.Fatalf("encode() error = %v", err)
+			}
+			if contentType != test.wantContentType {
+				t.Errorf("contentType = %q, want %q", contentType, test.wantContentType)
+			}
+			got, err := io.ReadAll(body)
+			if err != nil {
+				t.Fatalf("reading body: %v", err)
+			}
+			if string(got) != test.wantBody {
+				t.Errorf("body = %q, want %q", got, test.wantBody)
+			}
+		})
+	}
+}
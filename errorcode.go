@@ -0,0 +1,113 @@
+package captcha
+
+import "encoding/json"
+
+// ErrorCode identifies a single error returned by a captcha verify endpoint, in the `error-codes`
+// field of a `VerifyResponse`. This is a typed alternative to comparing raw strings against the
+// `ErrorCode...` constants below.
+type ErrorCode string
+
+// ErrorCodeMissingInputSecret is caused when the secret is not passed.
+const ErrorCodeMissingInputSecret ErrorCode = "missing-input-secret"
+
+// ErrorCodeInvalidInputSecret is caused when the secret is invalid.
+const ErrorCodeInvalidInputSecret ErrorCode = "invalid-input-secret"
+
+// ErrorCodeMissingInputResponse is caused when the response is not passed.
+const ErrorCodeMissingInputResponse ErrorCode = "missing-input-response"
+
+// ErrorCodeInvalidInputResponse is caused when the response is not valid.
+const ErrorCodeInvalidInputResponse ErrorCode = "invalid-input-response"
+
+// ErrorCodeBadRequest is caused by a malformed request.
+const ErrorCodeBadRequest ErrorCode = "bad-request"
+
+// ErrorCodeTimeoutOrDuplicate is caused when the response is either too old or has been used
+// previously.
+const ErrorCodeTimeoutOrDuplicate ErrorCode = "timeout-or-duplicate"
+
+// ErrorCodeInternalError is caused when an unknown internal error has occurred. The request can be
+// retried.
+const ErrorCodeInternalError ErrorCode = "internal-error"
+
+// ErrorCodeSitekeySecretMismatch is caused when the sitekey and secret are from different accounts.
+//
+// Returned by hCaptcha.
+const ErrorCodeSitekeySecretMismatch ErrorCode = "sitekey-secret-mismatch"
+
+// ErrorCodeInvalidOrAlreadySeenResponse is caused when the response is invalid or has already been
+// checked.
+//
+// Returned by hCaptcha.
+const ErrorCodeInvalidOrAlreadySeenResponse ErrorCode = "invalid-or-already-seen-response"
+
+// ErrorCodeNotUsingDummyPasscode is caused when a testing sitekey was used but the response isn't
+// the expected dummy passcode.
+//
+// Returned by hCaptcha.
+const ErrorCodeNotUsingDummyPasscode ErrorCode = "not-using-dummy-passcode"
+
+// ErrorCodeSitekeyMismatch is caused when the sitekey isn't registered with the used account.
+//
+// Returned by hCaptcha.
+const ErrorCodeSitekeyMismatch ErrorCode = "sitekey-mismatch"
+
+// IsClientError reports whether this error code indicates a problem with the client's request or
+// response token (bad input, a duplicate or expired token), as opposed to a problem with the
+// server's own secret or an internal failure.
+func (code ErrorCode) IsClientError() bool {
+	switch code {
+	case ErrorCodeMissingInputResponse,
+		ErrorCodeInvalidInputResponse,
+		ErrorCodeBadRequest,
+		ErrorCodeTimeoutOrDuplicate,
+		ErrorCodeInvalidOrAlreadySeenResponse,
+		ErrorCodeNotUsingDummyPasscode:
+		return true
+	default:
+		return false
+	}
+}
+
+// IsServerError reports whether this error code indicates a problem with the server's own
+// configuration (a missing or invalid secret) or an internal failure that's safe to retry.
+func (code ErrorCode) IsServerError() bool {
+	switch code {
+	case ErrorCodeMissingInputSecret,
+		ErrorCodeInvalidInputSecret,
+		ErrorCodeInternalError,
+		ErrorCodeSitekeySecretMismatch,
+		ErrorCodeSitekeyMismatch:
+		return true
+	default:
+		return false
+	}
+}
+
+// ErrorCodes is a list of `ErrorCode`s, as returned in `VerifyResponse.ErrorCodes`. It unmarshals
+// from the raw list of strings returned by every provider.
+type ErrorCodes []ErrorCode
+
+// UnmarshalJSON implements `json.Unmarshaler`, decoding the endpoint's raw `[]string` into typed
+// `ErrorCode`s.
+func (codes *ErrorCodes) UnmarshalJSON(data []byte) error {
+	var raw []string
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	result := make(ErrorCodes, len(raw))
+	for i, code := range raw {
+		result[i] = ErrorCode(code)
+	}
+	*codes = result
+	return nil
+}
+
+// FirstError returns the first entry in `resp.ErrorCodes`, or an empty `ErrorCode` if there are
+// none.
+func (resp *VerifyResponse) FirstError() ErrorCode {
+	if len(resp.ErrorCodes) == 0 {
+		return ""
+	}
+	return resp.ErrorCodes[0]
+}
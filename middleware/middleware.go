@@ -0,0 +1,123 @@
+// package middleware provides `net/http` middleware which verifies a captcha response on incoming
+// requests before passing them on to the wrapped handler.
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+
+	captcha "github.com/JOT85/captcha-go"
+)
+
+// Preset form field names for the providers `captcha` has first-class support for. These are the
+// field names the corresponding provider's client-side widget submits by default.
+const (
+	FieldGoogleRecaptcha     = "g-recaptcha-response"
+	FieldCloudflareTurnstile = "cf-turnstile-response"
+	FieldHCaptcha            = "h-captcha-response"
+)
+
+// config holds the middleware's settings, built up from `MiddlewareOption`s.
+type config struct {
+	formField string
+	ipHeader  string
+	onFailure func(http.ResponseWriter, *http.Request, error)
+}
+
+// MiddlewareOption configures `Middleware`.
+type MiddlewareOption func(*config)
+
+// WithFormField sets the form field the captcha response is read from. Defaults to
+// `FieldGoogleRecaptcha`; use `FieldCloudflareTurnstile` or `FieldHCaptcha` for those providers.
+func WithFormField(field string) MiddlewareOption {
+	return func(c *config) {
+		c.formField = field
+	}
+}
+
+// WithTrustedProxyHeader takes the client's remote IP from the named header (e.g.
+// `X-Forwarded-For` or `X-Real-IP`) instead of `http.Request.RemoteAddr`. Only set this if
+// requests genuinely pass through a proxy which sets this header, otherwise clients can spoof
+// their reported IP.
+func WithTrustedProxyHeader(header string) MiddlewareOption {
+	return func(c *config) {
+		c.ipHeader = header
+	}
+}
+
+// WithOnFailure overrides what happens when verification fails. The default responds with
+// `403 Forbidden` and a short plain-text body.
+func WithOnFailure(onFailure func(http.ResponseWriter, *http.Request, error)) MiddlewareOption {
+	return func(c *config) {
+		c.onFailure = onFailure
+	}
+}
+
+func defaultOnFailure(w http.ResponseWriter, r *http.Request, err error) {
+	http.Error(w, "captcha verification failed", http.StatusForbidden)
+}
+
+// Middleware returns `net/http` middleware which verifies the captcha response submitted with each
+// request using `verifier` before calling the wrapped handler. On success, the `*captcha.VerifyResponse`
+// is stored in the request's context and can be retrieved with `FromContext`.
+func Middleware(verifier *captcha.SimpleCaptchaVerifier, opts ...MiddlewareOption) func(http.Handler) http.Handler {
+	c := &config{
+		formField: FieldGoogleRecaptcha,
+		onFailure: defaultOnFailure,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			token := r.FormValue(c.formField)
+			resp, ok, err := verifier.VerifyActionWithResponseContext(
+				r.Context(), token, remoteIP(r, c.ipHeader), verifier.ExpectedAction,
+			)
+			if err == nil && !ok {
+				err = fmt.Errorf("captcha verification did not pass")
+			}
+			if err != nil {
+				c.onFailure(w, r, err)
+				return
+			}
+			next.ServeHTTP(w, r.WithContext(withVerifyResponse(r.Context(), resp)))
+		})
+	}
+}
+
+// remoteIP extracts the client's IP address from `r`, using `header` if set, or falling back to
+// `r.RemoteAddr`.
+func remoteIP(r *http.Request, header string) string {
+	if header != "" {
+		if value := r.Header.Get(header); value != "" {
+			ip := strings.TrimSpace(strings.SplitN(value, ",", 2)[0])
+			return ip
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// contextKey is an unexported type to avoid collisions with context keys from other packages.
+type contextKey struct{}
+
+var verifyResponseKey = contextKey{}
+
+func withVerifyResponse(ctx context.Context, resp *captcha.VerifyResponse) context.Context {
+	return context.WithValue(ctx, verifyResponseKey, resp)
+}
+
+// FromContext returns the `*captcha.VerifyResponse` stored by `Middleware` on a successful
+// verification, and whether one was found.
+func FromContext(ctx context.Context) (*captcha.VerifyResponse, bool) {
+	resp, ok := ctx.Value(verifyResponseKey).(*captcha.VerifyResponse)
+	return resp, ok
+}
@@ -0,0 +1,169 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	captcha "github.com/JOT85/captcha-go"
+)
+
+func TestRemoteIP(t *testing.T) {
+	tests := []struct {
+		name       string
+		remoteAddr string
+		header     string
+		setHeader  bool
+		headerVal  string
+		want       string
+	}{
+		{
+			name:       "no header configured uses RemoteAddr host",
+			remoteAddr: "203.0.113.1:12345",
+			want:       "203.0.113.1",
+		},
+		{
+			name:       "RemoteAddr without a port is used as-is",
+			remoteAddr: "203.0.113.1",
+			want:       "203.0.113.1",
+		},
+		{
+			name:       "trusted header takes the first entry",
+			remoteAddr: "10.0.0.1:1",
+			header:     "X-Forwarded-For",
+			setHeader:  true,
+			headerVal:  "203.0.113.9, 10.0.0.1",
+			want:       "203.0.113.9",
+		},
+		{
+			name:       "trusted header present but empty falls back to RemoteAddr",
+			remoteAddr: "203.0.113.1:12345",
+			header:     "X-Forwarded-For",
+			setHeader:  true,
+			headerVal:  "",
+			want:       "203.0.113.1",
+		},
+		{
+			name:       "trusted header configured but absent falls back to RemoteAddr",
+			remoteAddr: "203.0.113.1:12345",
+			header:     "X-Forwarded-For",
+			want:       "203.0.113.1",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.RemoteAddr = test.remoteAddr
+			if test.setHeader {
+				r.Header.Set(test.header, test.headerVal)
+			}
+
+			got := remoteIP(r, test.header)
+			if got != test.want {
+				t.Errorf("remoteIP() = %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+// fakeVerifyServer returns a test server acting as a captcha verify endpoint, always responding
+// with the given success value.
+func fakeVerifyServer(t *testing.T, success bool) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(captcha.VerifyResponse{Success: success})
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestMiddlewareSuccess(t *testing.T) {
+	server := fakeVerifyServer(t, true)
+	verifier := &captcha.SimpleCaptchaVerifier{
+		Verifier: *captcha.NewCaptchaVerifier(captcha.Endpoint(server.URL), "secret"),
+	}
+	verifier.Verifier.HttpClient = server.Client()
+
+	var gotResp *captcha.VerifyResponse
+	var gotOK bool
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotResp, gotOK = FromContext(r.Context())
+		w.WriteHeader(http.StatusOK)
+	})
+
+	form := url.Values{"g-recaptcha-response": {"token"}}
+	r := httptest.NewRequest(http.MethodPost, "/", nil)
+	r.PostForm = form
+	w := httptest.NewRecorder()
+
+	Middleware(verifier)(next).ServeHTTP(w, r)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", w.Code, http.StatusOK)
+	}
+	if !gotOK {
+		t.Fatal("FromContext: ok = false, want true")
+	}
+	if gotResp == nil || !gotResp.Success {
+		t.Fatalf("FromContext response = %+v, want Success = true", gotResp)
+	}
+}
+
+func TestMiddlewareFailureDefaultOnFailure(t *testing.T) {
+	server := fakeVerifyServer(t, false)
+	verifier := &captcha.SimpleCaptchaVerifier{
+		Verifier: *captcha.NewCaptchaVerifier(captcha.Endpoint(server.URL), "secret"),
+	}
+	verifier.Verifier.HttpClient = server.Client()
+
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Fatal("next handler should not be called on failed verification")
+	})
+
+	form := url.Values{"g-recaptcha-response": {"token"}}
+	r := httptest.NewRequest(http.MethodPost, "/", nil)
+	r.PostForm = form
+	w := httptest.NewRecorder()
+
+	Middleware(verifier)(next).ServeHTTP(w, r)
+
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("status = %d, want %d", w.Code, http.StatusForbidden)
+	}
+}
+
+func TestMiddlewareCustomOnFailure(t *testing.T) {
+	server := fakeVerifyServer(t, false)
+	verifier := &captcha.SimpleCaptchaVerifier{
+		Verifier: *captcha.NewCaptchaVerifier(captcha.Endpoint(server.URL), "secret"),
+	}
+	verifier.Verifier.HttpClient = server.Client()
+
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Fatal("next handler should not be called on failed verification")
+	})
+
+	called := false
+	onFailure := func(w http.ResponseWriter, r *http.Request, err error) {
+		called = true
+		w.WriteHeader(http.StatusTeapot)
+	}
+
+	form := url.Values{"g-recaptcha-response": {"token"}}
+	r := httptest.NewRequest(http.MethodPost, "/", nil)
+	r.PostForm = form
+	w := httptest.NewRecorder()
+
+	Middleware(verifier, WithOnFailure(onFailure))(next).ServeHTTP(w, r)
+
+	if !called {
+		t.Fatal("custom onFailure was not called")
+	}
+	if w.Code != http.StatusTeapot {
+		t.Fatalf("status = %d, want %d", w.Code, http.StatusTeapot)
+	}
+}
@@ -0,0 +1,206 @@
+package captcha
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// VerifyOption checks a single aspect of a `VerifyResponse`, returning a descriptive error if it
+// doesn't meet expectations. Use with `VerifyResponse.Check` or `CaptchaVerifier.VerifyAndCheck`.
+type VerifyOption func(*VerifyResponse) error
+
+// ErrVerificationFailed is returned by `Check` when `VerifyResponse.Success` is false.
+type ErrVerificationFailed struct {
+	// ErrorCodes is the response's `ErrorCodes`, copied for convenience.
+	ErrorCodes ErrorCodes
+}
+
+func (err ErrVerificationFailed) Error() string {
+	return fmt.Sprint("captcha verification failed, error codes:", err.ErrorCodes)
+}
+
+// ErrHostnameMismatch is returned when `VerifyResponse.Hostname` isn't one of the expected values.
+type ErrHostnameMismatch struct {
+	Got  string
+	Want []string
+}
+
+func (err ErrHostnameMismatch) Error() string {
+	return fmt.Sprintf("captcha hostname mismatch: got %q, want one of %q", err.Got, err.Want)
+}
+
+// ErrActionMismatch is returned when `VerifyResponse.Action` isn't one of the expected values.
+type ErrActionMismatch struct {
+	Got  string
+	Want []string
+}
+
+func (err ErrActionMismatch) Error() string {
+	return fmt.Sprintf("captcha action mismatch: got %q, want one of %q", err.Got, err.Want)
+}
+
+// ErrApkPackageNameMismatch is returned when `VerifyResponse.ApkPackageName` doesn't match.
+type ErrApkPackageNameMismatch struct {
+	Got, Want string
+}
+
+func (err ErrApkPackageNameMismatch) Error() string {
+	return fmt.Sprintf("captcha apk package name mismatch: got %q, want %q", err.Got, err.Want)
+}
+
+// ErrScoreBelowThreshold is returned when `VerifyResponse.Score` is below the required minimum.
+type ErrScoreBelowThreshold struct {
+	Score, MinScore float32
+}
+
+func (err ErrScoreBelowThreshold) Error() string {
+	return fmt.Sprintf("captcha score %v is below the minimum of %v", err.Score, err.MinScore)
+}
+
+// ErrChallengeExpired is returned when a challenge is older than the configured maximum age, or
+// when its age can't be determined and one is required.
+type ErrChallengeExpired struct {
+	// Age is the challenge's age, or zero if it couldn't be determined.
+	Age time.Duration
+
+	// Max is the configured maximum age.
+	Max time.Duration
+}
+
+func (err ErrChallengeExpired) Error() string {
+	if err.Age == 0 {
+		return fmt.Sprintf("captcha challenge age is unknown, maximum allowed is %v", err.Max)
+	}
+	return fmt.Sprintf("captcha challenge age %v exceeds maximum of %v", err.Age, err.Max)
+}
+
+// ErrHasErrorCodes is returned when `VerifyResponse.ErrorCodes` is non-empty.
+type ErrHasErrorCodes struct {
+	ErrorCodes ErrorCodes
+}
+
+func (err ErrHasErrorCodes) Error() string {
+	return fmt.Sprint("captcha response has error codes:", err.ErrorCodes)
+}
+
+// WithHostname requires `VerifyResponse.Hostname` to equal `hostname`.
+func WithHostname(hostname string) VerifyOption {
+	return WithHostnames(hostname)
+}
+
+// WithHostnames requires `VerifyResponse.Hostname` to equal one of `hostnames`, for deployments
+// which serve the same site from multiple hostnames.
+func WithHostnames(hostnames ...string) VerifyOption {
+	return func(resp *VerifyResponse) error {
+		for _, want := range hostnames {
+			if resp.Hostname == want {
+				return nil
+			}
+		}
+		return ErrHostnameMismatch{Got: resp.Hostname, Want: hostnames}
+	}
+}
+
+// WithAction requires `VerifyResponse.Action` to equal `action`.
+func WithAction(action string) VerifyOption {
+	return WithActions(action)
+}
+
+// WithActions requires `VerifyResponse.Action` to equal one of `actions`, for routes which accept
+// more than one action.
+func WithActions(actions ...string) VerifyOption {
+	return func(resp *VerifyResponse) error {
+		for _, want := range actions {
+			if resp.Action == want {
+				return nil
+			}
+		}
+		return ErrActionMismatch{Got: resp.Action, Want: actions}
+	}
+}
+
+// WithApkPackageName requires `VerifyResponse.ApkPackageName` to equal `apkPackageName`.
+func WithApkPackageName(apkPackageName string) VerifyOption {
+	return func(resp *VerifyResponse) error {
+		if resp.ApkPackageName != apkPackageName {
+			return ErrApkPackageNameMismatch{Got: resp.ApkPackageName, Want: apkPackageName}
+		}
+		return nil
+	}
+}
+
+// WithMinScore requires `VerifyResponse.Score` to be at least `minScore`. Only reCAPTCHA v3
+// populates `Score`; leave this unset (or pass 0) for reCAPTCHA v2 and Turnstile.
+func WithMinScore(minScore float32) VerifyOption {
+	return func(resp *VerifyResponse) error {
+		if resp.Score < minScore {
+			return ErrScoreBelowThreshold{Score: resp.Score, MinScore: minScore}
+		}
+		return nil
+	}
+}
+
+// WithMaxChallengeAge requires `VerifyResponse.ChallengeTime` to be no older than `maxAge`,
+// rejecting replayed challenge tokens. It fails closed: a missing or unparseable
+// `ChallengeTime` is treated as expired. `maxAge <= 0` disables the check, matching
+// `SimpleCaptchaVerifier.MaxChallengeAge`.
+//
+// The "current time" is `CaptchaVerifier.Now`, the same injectable clock used by
+// `SimpleCaptchaVerifier.MaxChallengeAge`, so both APIs agree and both are testable.
+func WithMaxChallengeAge(maxAge time.Duration) VerifyOption {
+	return func(resp *VerifyResponse) error {
+		if maxAge <= 0 {
+			return nil
+		}
+		ts, err := resp.ParsedChallengeTime()
+		if err != nil {
+			return ErrChallengeExpired{Max: maxAge}
+		}
+		age := resp.now().Sub(ts)
+		if age > maxAge {
+			return ErrChallengeExpired{Age: age, Max: maxAge}
+		}
+		return nil
+	}
+}
+
+// WithRequireNoErrorCodes requires `VerifyResponse.ErrorCodes` to be empty.
+func WithRequireNoErrorCodes() VerifyOption {
+	return func(resp *VerifyResponse) error {
+		if len(resp.ErrorCodes) != 0 {
+			return ErrHasErrorCodes{ErrorCodes: resp.ErrorCodes}
+		}
+		return nil
+	}
+}
+
+// Check runs `opts` against this `VerifyResponse` in order, stopping and returning the first
+// error. `Success` is always checked first, before any `opts` run.
+func (resp *VerifyResponse) Check(opts ...VerifyOption) error {
+	if !resp.Success {
+		return ErrVerificationFailed{ErrorCodes: resp.ErrorCodes}
+	}
+	for _, opt := range opts {
+		if err := opt(resp); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// VerifyAndCheck verifies a captcha response and checks it against `opts`, combining `VerifyContext`
+// and `VerifyResponse.Check`. It lets a network error, a failed challenge, and a mismatched
+// expectation (wrong hostname, action, etc.) all be distinguished via the returned error's type.
+func (client *CaptchaVerifier) VerifyAndCheck(
+	ctx context.Context,
+	clientResponse,
+	remoteIP string,
+	opts ...VerifyOption,
+) (*VerifyResponse, error) {
+	resp, err := client.VerifyContext(ctx, clientResponse, remoteIP)
+	if err != nil {
+		return resp, err
+	}
+	return resp, resp.Check(opts...)
+}
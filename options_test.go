@@ -0,0 +1,83 @@
+package captcha
+
+import (
+	"testing"
+	"time"
+)
+
+func TestWithMaxChallengeAge(t *testing.T) {
+	now := time.Date(2026, 7, 25, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name          string
+		maxAge        time.Duration
+		challengeTime string
+		wantErr       bool
+		wantAge       time.Duration
+	}{
+		{
+			name:          "disabled when zero",
+			maxAge:        0,
+			challengeTime: "",
+			wantErr:       false,
+		},
+		{
+			name:          "disabled when negative",
+			maxAge:        -time.Minute,
+			challengeTime: "",
+			wantErr:       false,
+		},
+		{
+			name:          "missing challenge time fails closed",
+			maxAge:        time.Minute,
+			challengeTime: "",
+			wantErr:       true,
+		},
+		{
+			name:          "within max age",
+			maxAge:        time.Minute,
+			challengeTime: now.Add(-30 * time.Second).Format(time.RFC3339),
+			wantErr:       false,
+		},
+		{
+			name:          "older than max age",
+			maxAge:        time.Minute,
+			challengeTime: now.Add(-2 * time.Minute).Format(time.RFC3339),
+			wantErr:       true,
+			wantAge:       2 * time.Minute,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			resp := &VerifyResponse{
+				Success:       true,
+				ChallengeTime: test.challengeTime,
+				nowFunc:       func() time.Time { return now },
+			}
+
+			err := resp.Check(WithMaxChallengeAge(test.maxAge))
+			if test.wantErr != (err != nil) {
+				t.Fatalf("Check() error = %v, wantErr %v", err, test.wantErr)
+			}
+			if expired, ok := err.(ErrChallengeExpired); ok && expired.Age != test.wantAge {
+				t.Errorf("ErrChallengeExpired.Age = %v, want %v", expired.Age, test.wantAge)
+			}
+		})
+	}
+}
+
+func TestWithMaxChallengeAgeUsesInjectedClock(t *testing.T) {
+	// A challenge that's "fresh" under a fixed clock but would be ancient under the real one,
+	// proving the check reads resp.now() rather than time.Now()/time.Since directly.
+	fixedNow := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	resp := &VerifyResponse{
+		Success:       true,
+		ChallengeTime: fixedNow.Add(-time.Second).Format(time.RFC3339),
+		nowFunc:       func() time.Time { return fixedNow },
+	}
+
+	if err := resp.Check(WithMaxChallengeAge(time.Minute)); err != nil {
+		t.Fatalf("Check() = %v, want nil", err)
+	}
+}